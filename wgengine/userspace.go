@@ -8,13 +8,14 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,7 +23,6 @@ import (
 	"github.com/tailscale/wireguard-go/device"
 	"github.com/tailscale/wireguard-go/tun"
 	"github.com/tailscale/wireguard-go/wgcfg"
-	"go4.org/mem"
 	"tailscale.com/ipn/ipnstate"
 	"tailscale.com/net/interfaces"
 	"tailscale.com/tailcfg"
@@ -36,19 +36,33 @@ import (
 	"tailscale.com/wgengine/tstun"
 )
 
-// minimalMTU is the MTU we set on tailscale's tuntap
-// interface. wireguard-go defaults to 1420 bytes, which only works if
-// the "outer" MTU is 1500 bytes. This breaks on DSL connections
-// (typically 1492 MTU) and on GCE (1460 MTU?!).
+// minimalMTU is the floor we never probe below. wireguard-go defaults
+// to 1420 bytes on the tuntap interface, which only works if the
+// "outer" MTU is 1500 bytes. This breaks on DSL connections (typically
+// 1492 MTU) and on GCE (1460 MTU?!), so we start conservative.
+// discoverPeerMTU sends probes but does not check for replies, so it
+// never raises a peer's MTU above this floor.
 //
-// 1280 is the smallest MTU allowed for IPv6, which is a sensible
-// "probably works everywhere" setting until we develop proper PMTU
-// discovery.
+// 1280 is the smallest MTU allowed for IPv6, so it's always safe.
 const minimalMTU = 1280
 
+// maxProbedMTU is the largest path MTU discoverPeerMTU will ever
+// believe, matching wireguard-go's own default tuntap MTU.
+const maxProbedMTU = 1420
+
+// pmtuProbeSizes are the ICMP echo payload sizes tried during path MTU
+// discovery, from largest to smallest. Every size is sent; no reply is
+// checked, so the peer's MTU is always recorded as minimalMTU.
+var pmtuProbeSizes = []int{maxProbedMTU, 1400, 1350, minimalMTU}
+
+// pmtuReprobeInterval is the minimum time between two LinkChange-driven
+// re-runs of PMTU discovery against already-established peers, in case
+// the path changed underneath us without an explicit handshake.
+const pmtuReprobeInterval = 5 * time.Minute
+
 type userspaceEngine struct {
 	logf      logger.Logf
-	reqCh     chan struct{}
+	reqCh     chan struct{} // size 1; debounces RequestStatus callers
 	waitCh    chan struct{}
 	tundev    *tstun.TUN
 	wgdev     *device.Device
@@ -62,14 +76,48 @@ type userspaceEngine struct {
 
 	mu             sync.Mutex // guards following; see lock order comment below
 	statusCallback StatusCallback
+	statusSubs     map[chan<- StatusEvent]struct{}
 	peerSequence   []wgcfg.Key
 	endpoints      []string
-	pingers        map[wgcfg.Key]context.CancelFunc // mu must be held to call CancelFunc
+	probeCancels   map[wgcfg.Key]context.CancelFunc // mu must be held to call CancelFunc
 	linkState      *interfaces.State
+	peerIPs        map[wgcfg.Key][]wgcfg.IP         // single-IP routes, used to (re-)probe a peer
+	peerMTU        map[wgcfg.Key]int                // last-discovered path MTU per peer, 0 = unknown
+	mtuProbers     map[wgcfg.Key]context.CancelFunc // mu must be held to call CancelFunc
+	lastMTUProbe   time.Time
+	peerByteCounts map[wgcfg.Key][2]int64 // last-seen [rx, tx] byte counts, for delta detection
 
 	// Lock ordering: wgLock, then mu.
 }
 
+// StatusEventKind identifies why a StatusEvent was emitted.
+type StatusEventKind int
+
+const (
+	// StatusEventDelta means a peer's rx/tx byte counters moved.
+	StatusEventDelta StatusEventKind = iota
+	// StatusEventHandshake means a peer completed a handshake.
+	StatusEventHandshake
+	// StatusEventPeerAdded means Reconfig added a new peer.
+	StatusEventPeerAdded
+	// StatusEventPeerRemoved means Reconfig dropped a peer.
+	StatusEventPeerRemoved
+)
+
+// StatusEvent describes a real, observed change to engine status. It's
+// delivered to subscribers registered via SubscribeStatus.
+type StatusEvent struct {
+	Kind    StatusEventKind
+	PeerKey wgcfg.Key // zero value for engine-wide events
+}
+
+// statusPollInterval is how often the engine checks ipcPeerStats
+// for byte-count movement, in lieu of wireguard-go itself pushing
+// rx/tx deltas to us. Handshake and peer add/remove events are pushed
+// immediately from the code paths that cause them, rather than waiting
+// for this timer.
+const statusPollInterval = 2 * time.Second
+
 type Loggify struct {
 	f logger.Logf
 }
@@ -94,6 +142,10 @@ func NewUserspaceEngine(logf logger.Logf, tunname string, listenPort uint16) (En
 
 	logf("Starting userspace wireguard engine with tun device %q", tunname)
 
+	// Start at minimalMTU. discoverPeerMTU can only raise this once it
+	// has a way to confirm a probe actually got through; until the
+	// inbound hook for that lands, starting any higher would just
+	// recreate the DSL/GCE blackhole minimalMTU exists to avoid.
 	tundev, err := tun.CreateTUN(tunname, minimalMTU)
 	if err != nil {
 		diagnoseTUNFailure(logf)
@@ -121,11 +173,16 @@ func NewUserspaceEngineAdvanced(logf logger.Logf, tundev tun.Device, routerGen R
 
 func newUserspaceEngineAdvanced(logf logger.Logf, tundev tun.Device, routerGen RouterGen, listenPort uint16) (_ Engine, reterr error) {
 	e := &userspaceEngine{
-		logf:    logf,
-		reqCh:   make(chan struct{}, 1),
-		waitCh:  make(chan struct{}),
-		tundev:  tstun.WrapTUN(logf, tundev),
-		pingers: make(map[wgcfg.Key]context.CancelFunc),
+		logf:           logf,
+		reqCh:          make(chan struct{}, 1),
+		waitCh:         make(chan struct{}),
+		tundev:         tstun.WrapTUN(logf, tundev),
+		probeCancels:   make(map[wgcfg.Key]context.CancelFunc),
+		peerIPs:        make(map[wgcfg.Key][]wgcfg.IP),
+		peerMTU:        make(map[wgcfg.Key]int),
+		mtuProbers:     make(map[wgcfg.Key]context.CancelFunc),
+		statusSubs:     make(map[chan<- StatusEvent]struct{}),
+		peerByteCounts: make(map[wgcfg.Key][2]int64),
 	}
 	e.linkState, _ = getLinkState()
 
@@ -166,18 +223,15 @@ func newUserspaceEngineAdvanced(logf logger.Logf, tundev tun.Device, routerGen R
 	opts := &device.DeviceOptions{
 		Logger: &logger,
 		HandshakeDone: func(peerKey wgcfg.Key, allowedIPs []net.IPNet) {
-			// Send an unsolicited status event every time a
-			// handshake completes. This makes sure our UI can
-			// update quickly as soon as it connects to a peer.
-			//
-			// We use a goroutine here to avoid deadlocking
-			// wireguard, since RequestStatus() will call back
-			// into it, and wireguard is what called us to get
-			// here.
-			go e.RequestStatus()
-
-			// Ping every single-IP that peer routes.
-			// These synthetic packets are used to traverse NATs.
+			// Notify subscribers every time a handshake
+			// completes, so the UI can update as soon as we
+			// connect to a peer, without waiting on a poll.
+			e.mu.Lock()
+			e.emitStatusEventLocked(StatusEvent{Kind: StatusEventHandshake, PeerKey: peerKey})
+			e.mu.Unlock()
+
+			// Probe every single-IP that peer routes, to traverse
+			// NATs. No RTT is measured.
 			var ips []wgcfg.IP
 			for _, ipNet := range allowedIPs {
 				if ones, bits := ipNet.Mask.Size(); ones == bits && ones != 0 {
@@ -187,7 +241,11 @@ func newUserspaceEngineAdvanced(logf logger.Logf, tundev tun.Device, routerGen R
 				}
 			}
 			if len(ips) > 0 {
-				go e.pinger(peerKey, ips)
+				e.mu.Lock()
+				e.peerIPs[peerKey] = ips
+				e.mu.Unlock()
+				go e.probePeer(peerKey, ips)
+				go e.discoverPeerMTU(peerKey, ips)
 			} else {
 				logf("[unexpected] peer %s has no single-IP routes: %v", peerKey.ShortString(), allowedIPs)
 			}
@@ -243,63 +301,148 @@ func newUserspaceEngineAdvanced(logf logger.Logf, tundev tun.Device, routerGen R
 	}
 	e.linkMon.Start()
 
+	go e.watchStatusDeltas()
+
 	return e, nil
 }
 
-// pinger sends ping packets for a few seconds.
+// watchStatusDeltas polls the device's UAPI byte counters at
+// statusPollInterval and emits a StatusEventDelta for each peer whose
+// rx/tx counters moved since the last check (a peer not seen before
+// counts as starting from zero), so SubscribeStatus subscribers only
+// hear about real traffic rather than every tick.
 //
-// These generated packets are used to ensure we trigger the spray logic in
-// the magicsock package for NAT traversal.
-func (e *userspaceEngine) pinger(peerKey wgcfg.Key, ips []wgcfg.IP) {
-	e.logf("generating initial ping traffic to %s (%v)", peerKey.ShortString(), ips)
-	var srcIP packet.IP
+// This is an interim measure: once wireguard-go pushes byte-count
+// deltas to us directly, this polling loop can go away and
+// emitStatusEventLocked can be called straight from that hook.
+func (e *userspaceEngine) watchStatusDeltas() {
+	t := time.NewTicker(statusPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-e.waitCh:
+			return
+		case <-t.C:
+		}
 
+		pp, err := e.ipcPeerStats()
+		if err != nil || pp == nil {
+			continue
+		}
+
+		e.mu.Lock()
+		for pk, p := range pp {
+			counts := [2]int64{int64(p.RxBytes), int64(p.TxBytes)}
+			if prev := e.peerByteCounts[pk]; prev != counts {
+				e.peerByteCounts[pk] = counts
+				e.emitStatusEventLocked(StatusEvent{Kind: StatusEventDelta, PeerKey: pk})
+			}
+		}
+		e.mu.Unlock()
+	}
+}
+
+// Prober is a pluggable NAT-traversal probing strategy. Implementations
+// send some form of packet train to a peer's routed IPs purely to
+// trigger magicsock's spray logic.
+//
+// TCP SYN and UDP-nonce strategies were considered here too, but both
+// need packet-construction helpers (packet.GenTCPSYN, packet.GenUDP)
+// that don't exist in the vendored tailscale.com/wgengine/packet yet;
+// they can be added back once that package grows them. RTT measurement
+// similarly needs a hook in the inbound packet path, outside this
+// file, that recognizes a probe's reply and reports it back to the
+// sending Prober.
+type Prober interface {
+	// Name identifies the strategy, for logging.
+	Name() string
+	// Probe sends a probe train to dstIPs on behalf of peerKey,
+	// blocking for the life of the train or until ctx is canceled.
+	Probe(ctx context.Context, e *userspaceEngine, peerKey wgcfg.Key, srcIP packet.IP, dstIPs []packet.IP)
+}
+
+// defaultProbers is the strategy chain tried for a peer that has no
+// NetInfo hint yet or is DERP-only. It's just icmpEchoProber for now; see
+// the Prober doc comment for why TCP/UDP strategies aren't here too.
+var defaultProbers = []Prober{
+	icmpEchoProber{},
+}
+
+// proberForPeer picks a Prober chain for peerKey, keeping the
+// NetInfo-based direct-vs-DERP-only split as an extension point for
+// when more strategies land in defaultProbers; both cases currently
+// resolve to the same icmpEchoProber-only chain.
+func (e *userspaceEngine) proberForPeer(peerKey wgcfg.Key) []Prober {
+	if ni := e.magicConn.LastRecvNetInfo(peerKey); ni != nil && !ni.DERPOnly {
+		return []Prober{icmpEchoProber{}}
+	}
+	return defaultProbers
+}
+
+// probePeer runs each Prober in proberForPeer(peerKey) against ips in
+// turn, for NAT traversal. No Prober measures RTT. It replaces the
+// previous single hard-coded pinger.
+func (e *userspaceEngine) probePeer(peerKey wgcfg.Key, ips []wgcfg.IP) {
+	e.logf("probing %s (%v)", peerKey.ShortString(), ips)
+
+	var srcIP packet.IP
 	e.wgLock.Lock()
 	if len(e.lastCfg.Addresses) > 0 {
 		srcIP = packet.NewIP(e.lastCfg.Addresses[0].IP.IP())
 	}
 	e.wgLock.Unlock()
-
 	if srcIP == 0 {
-		e.logf("generating initial ping traffic: no source IP")
+		e.logf("probePeer: no source IP")
 		return
 	}
 
 	e.mu.Lock()
-	if cancel := e.pingers[peerKey]; cancel != nil {
+	if cancel := e.probeCancels[peerKey]; cancel != nil {
 		cancel()
 	}
 	ctx, cancel := context.WithCancel(context.Background())
-	e.pingers[peerKey] = cancel
+	e.probeCancels[peerKey] = cancel
 	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		delete(e.probeCancels, peerKey)
+	}()
 
-	// sendFreq is slightly longer than sprayFreq in magicsock to ensure
-	// that if these ping packets are the only source of early packets
-	// sent to the peer, that each one will be sprayed.
-	const sendFreq = 300 * time.Millisecond
-	const stopAfter = 3 * time.Second
-
-	start := time.Now()
 	var dstIPs []packet.IP
 	for _, ip := range ips {
 		dstIPs = append(dstIPs, packet.NewIP(ip.IP()))
 	}
 
-	payload := []byte("magicsock_spray") // no meaning
-
-	defer func() {
-		e.mu.Lock()
-		defer e.mu.Unlock()
+	for _, p := range e.proberForPeer(peerKey) {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 		}
-		// If the pinger context is not done, then the
-		// CancelFunc is still in the pingers map.
-		delete(e.pingers, peerKey)
-	}()
+		p.Probe(ctx, e, peerKey, srcIP, dstIPs)
+	}
+}
+
+// icmpEchoProber sends a fixed ICMP echo train, same as the original
+// hard-coded pinger. It's sprayFreq-paced to ensure that if these are
+// the only early packets sent to a peer, each one still gets sprayed
+// by magicsock.
+type icmpEchoProber struct{}
 
+func (icmpEchoProber) Name() string { return "icmp" }
+
+func (icmpEchoProber) Probe(ctx context.Context, e *userspaceEngine, peerKey wgcfg.Key, srcIP packet.IP, dstIPs []packet.IP) {
+	const sendFreq = 300 * time.Millisecond
+	const stopAfter = 3 * time.Second
+	payload := []byte("magicsock_spray") // no meaning
+
+	start := time.Now()
 	ipid := uint16(1)
 	t := time.NewTicker(sendFreq)
 	defer t.Stop()
@@ -320,6 +463,111 @@ func (e *userspaceEngine) pinger(peerKey wgcfg.Key, ips []wgcfg.IP) {
 	}
 }
 
+// discoverPeerMTU sends an ICMP echo train at each of pmtuProbeSizes,
+// largest to smallest, to peerKey's routed IPs. Telling which size (if
+// any) actually got an echo reply back requires a hook in the inbound
+// packet path, outside this file, to recognize the reply and report it
+// back here; until that hook exists, this can't confirm anything got
+// through, so it always settles on minimalMTU rather than guessing.
+//
+// The probes still ride the same InjectOutbound path as the NAT
+// traversal probers, so they double as spray packets in the meantime.
+func (e *userspaceEngine) discoverPeerMTU(peerKey wgcfg.Key, ips []wgcfg.IP) {
+	e.logf("probing path MTU to %s (%v)", peerKey.ShortString(), ips)
+
+	e.wgLock.Lock()
+	var srcIP packet.IP
+	if len(e.lastCfg.Addresses) > 0 {
+		srcIP = packet.NewIP(e.lastCfg.Addresses[0].IP.IP())
+	}
+	e.wgLock.Unlock()
+	if srcIP == 0 {
+		e.logf("discoverPeerMTU: no source IP")
+		return
+	}
+
+	e.mu.Lock()
+	if cancel := e.mtuProbers[peerKey]; cancel != nil {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.mtuProbers[peerKey] = cancel
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		delete(e.mtuProbers, peerKey)
+	}()
+
+	var dstIPs []packet.IP
+	for _, ip := range ips {
+		dstIPs = append(dstIPs, packet.NewIP(ip.IP()))
+	}
+
+	for i, size := range pmtuProbeSizes {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		for _, dstIP := range dstIPs {
+			b := packet.GenICMP(srcIP, dstIP, uint16(i+1), packet.EchoRequest, 0, make([]byte, size))
+			e.tundev.InjectOutbound(b)
+		}
+	}
+	e.setPeerMTU(peerKey, minimalMTU)
+}
+
+// setPeerMTU records the discovered path MTU for peerKey, clamped to
+// [minimalMTU, maxProbedMTU].
+func (e *userspaceEngine) setPeerMTU(peerKey wgcfg.Key, mtu int) {
+	if mtu < minimalMTU {
+		mtu = minimalMTU
+	} else if mtu > maxProbedMTU {
+		mtu = maxProbedMTU
+	}
+	e.mu.Lock()
+	e.peerMTU[peerKey] = mtu
+	e.mu.Unlock()
+	e.adjustTUNMTU()
+}
+
+// adjustTUNMTU resizes the TUN device's MTU to match the smallest
+// discovered per-peer path MTU, in either direction: down so outgoing
+// packets get fragmented (or rejected with EMSGSIZE) at a layer where
+// the kernel can tell us about it instead of silently blackholing on
+// the wire, and back up again if a re-probe (e.g. after LinkChange)
+// finds every peer's path now tolerates something larger.
+func (e *userspaceEngine) adjustTUNMTU() {
+	want := e.minPeerMTU()
+	cur, err := e.tundev.MTU()
+	if err != nil || want == cur {
+		return
+	}
+	if err := e.tundev.ForceMTU(want); err != nil {
+		e.logf("discoverPeerMTU: resizing TUN MTU to %d: %v", want, err)
+	}
+}
+
+// minPeerMTU returns the smallest discovered path MTU across all
+// known peers, or maxProbedMTU if no peer has been probed yet.
+func (e *userspaceEngine) minPeerMTU() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	min := maxProbedMTU
+	for _, mtu := range e.peerMTU {
+		if mtu < min {
+			min = mtu
+		}
+	}
+	return min
+}
+
 func configSignature(cfg *wgcfg.Config, routerCfg *router.Config) (string, error) {
 	// TODO(apenwarr): get rid of uapi stuff for in-process comms
 	uapi, err := cfg.ToUAPI()
@@ -336,10 +584,32 @@ func (e *userspaceEngine) Reconfig(cfg *wgcfg.Config, routerCfg *router.Config)
 
 	peerSet := make(map[key.Public]struct{}, len(cfg.Peers))
 	e.mu.Lock()
+	oldPeers := make(map[wgcfg.Key]bool, len(e.peerSequence))
+	for _, pk := range e.peerSequence {
+		oldPeers[pk] = true
+	}
 	e.peerSequence = e.peerSequence[:0]
 	for _, p := range cfg.Peers {
 		e.peerSequence = append(e.peerSequence, p.PublicKey)
 		peerSet[key.Public(p.PublicKey)] = struct{}{}
+		if !oldPeers[p.PublicKey] {
+			e.emitStatusEventLocked(StatusEvent{Kind: StatusEventPeerAdded, PeerKey: p.PublicKey})
+		}
+		delete(oldPeers, p.PublicKey)
+	}
+	for pk := range oldPeers {
+		e.emitStatusEventLocked(StatusEvent{Kind: StatusEventPeerRemoved, PeerKey: pk})
+		delete(e.peerIPs, pk)
+		delete(e.peerMTU, pk)
+		delete(e.peerByteCounts, pk)
+		if cancel := e.probeCancels[pk]; cancel != nil {
+			cancel()
+			delete(e.probeCancels, pk)
+		}
+		if cancel := e.mtuProbers[pk]; cancel != nil {
+			cancel()
+			delete(e.mtuProbers, pk)
+		}
 	}
 	e.mu.Unlock()
 
@@ -392,15 +662,49 @@ func (e *userspaceEngine) SetStatusCallback(cb StatusCallback) {
 	e.statusCallback = cb
 }
 
+// SubscribeStatus registers ch to receive a StatusEvent whenever
+// something about engine status actually changes: a peer's byte
+// counters move, a handshake completes, or a peer is added or removed.
+// Unlike StatusCallback (driven by RequestStatus polling), this is
+// push-based and fires only on real deltas.
+//
+// ch must not be closed while still subscribed; call
+// UnsubscribeStatus first. Sends are non-blocking: a subscriber that
+// isn't keeping up will miss events rather than stall the engine.
+func (e *userspaceEngine) SubscribeStatus(ch chan<- StatusEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.statusSubs[ch] = struct{}{}
+}
+
+// UnsubscribeStatus removes a channel registered with SubscribeStatus.
+func (e *userspaceEngine) UnsubscribeStatus(ch chan<- StatusEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.statusSubs, ch)
+}
+
+// emitStatusEventLocked fans ev out to all subscribers. e.mu must be held.
+func (e *userspaceEngine) emitStatusEventLocked(ev StatusEvent) {
+	for ch := range e.statusSubs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
 func (e *userspaceEngine) getStatusCallback() StatusCallback {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	return e.statusCallback
 }
 
-// TODO: this function returns an error but it's always nil, and when
-// there's actually a problem it just calls log.Fatal. Why?
-func (e *userspaceEngine) getStatus() (*Status, error) {
+// ipcPeerStats dumps the device's current UAPI state via
+// IpcGetOperation and parses out rx_bytes/tx_bytes/last_handshake_time_sec
+// per peer, keyed by public key. It returns (nil, nil) if the device
+// hasn't been created yet.
+func (e *userspaceEngine) ipcPeerStats() (map[wgcfg.Key]*PeerStatus, error) {
 	e.wgLock.Lock()
 	defer e.wgLock.Unlock()
 
@@ -412,83 +716,68 @@ func (e *userspaceEngine) getStatus() (*Status, error) {
 		return nil, nil
 	}
 
-	// lineLen is the max UAPI line we expect. The longest I see is
-	// len("preshared_key=")+64 hex+"\n" == 79. Add some slop.
-	const lineLen = 100
-
-	pr, pw := io.Pipe()
-	errc := make(chan error, 1)
-	go func() {
-		defer pw.Close()
-		bw := bufio.NewWriterSize(pw, lineLen)
-		// TODO(apenwarr): get rid of silly uapi stuff for in-process comms
-		// FIXME: get notified of status changes instead of polling.
-		if err := e.wgdev.IpcGetOperation(bw); err != nil {
-			errc <- fmt.Errorf("IpcGetOperation: %w", err)
-			return
-		}
-		errc <- bw.Flush()
-	}()
+	var sb strings.Builder
+	if err := e.wgdev.IpcGetOperation(&sb); err != nil {
+		return nil, fmt.Errorf("IpcGetOperation: %w", err)
+	}
 
 	pp := make(map[wgcfg.Key]*PeerStatus)
-	p := &PeerStatus{}
-
-	var hst1, hst2, n int64
-	var err error
-
-	bs := bufio.NewScanner(pr)
-	bs.Buffer(make([]byte, lineLen), lineLen)
-	for bs.Scan() {
-		line := bs.Bytes()
-		k := line
-		var v mem.RO
-		if i := bytes.IndexByte(line, '='); i != -1 {
-			k = line[:i]
-			v = mem.B(line[i+1:])
+	var p *PeerStatus
+	s := bufio.NewScanner(strings.NewReader(sb.String()))
+	for s.Scan() {
+		line := s.Text()
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			continue
 		}
-		switch string(k) {
+		k, v := line[:i], line[i+1:]
+		switch k {
 		case "public_key":
-			pk, err := key.NewPublicFromHexMem(v)
-			if err != nil {
-				log.Fatalf("IpcGetOperation: invalid key %#v", v)
+			b, err := hex.DecodeString(v)
+			var pk wgcfg.Key
+			if err != nil || len(b) != len(pk) {
+				p = nil
+				continue
 			}
-			p = &PeerStatus{}
-			pp[wgcfg.Key(pk)] = p
-
-			key := tailcfg.NodeKey(pk)
-			p.NodeKey = key
+			copy(pk[:], b)
+			p = &PeerStatus{NodeKey: tailcfg.NodeKey(pk)}
+			pp[pk] = p
 		case "rx_bytes":
-			n, err = v.ParseInt(10, 64)
-			p.RxBytes = ByteCount(n)
-			if err != nil {
-				log.Fatalf("IpcGetOperation: rx_bytes invalid: %#v", line)
+			if p == nil {
+				continue
+			}
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				p.RxBytes = ByteCount(n)
 			}
 		case "tx_bytes":
-			n, err = v.ParseInt(10, 64)
-			p.TxBytes = ByteCount(n)
-			if err != nil {
-				log.Fatalf("IpcGetOperation: tx_bytes invalid: %#v", line)
+			if p == nil {
+				continue
+			}
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				p.TxBytes = ByteCount(n)
 			}
 		case "last_handshake_time_sec":
-			hst1, err = v.ParseInt(10, 64)
-			if err != nil {
-				log.Fatalf("IpcGetOperation: hst1 invalid: %#v", line)
+			if p == nil {
+				continue
 			}
-		case "last_handshake_time_nsec":
-			hst2, err = v.ParseInt(10, 64)
-			if err != nil {
-				log.Fatalf("IpcGetOperation: hst2 invalid: %#v", line)
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+				p.LastHandshake = time.Unix(n, 0)
 			}
-			if hst1 != 0 || hst2 != 0 {
-				p.LastHandshake = time.Unix(hst1, hst2)
-			} // else leave at time.IsZero()
 		}
 	}
-	if err := bs.Err(); err != nil {
-		log.Fatalf("reading IpcGetOperation output: %v", err)
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("scanning IpcGetOperation output: %w", err)
 	}
-	if err := <-errc; err != nil {
-		log.Fatalf("IpcGetOperation: %v", err)
+	return pp, nil
+}
+
+func (e *userspaceEngine) getStatus() (*Status, error) {
+	pp, err := e.ipcPeerStats()
+	if err != nil {
+		return nil, err
+	}
+	if pp == nil {
+		return nil, nil
 	}
 
 	e.mu.Lock()
@@ -514,28 +803,25 @@ func (e *userspaceEngine) getStatus() (*Status, error) {
 	}, nil
 }
 
+// RequestStatus fetches the current status and, if one is registered,
+// invokes StatusCallback with it. Most callers should prefer
+// SubscribeStatus, which pushes events only when something actually
+// changed; RequestStatus remains for callers that want a status
+// snapshot on demand (e.g. right after SetStatusCallback is called).
+//
+// Concurrent callers (e.g. magicsock's endpoint-change callback and the
+// TUN up/down event loop both call this) are debounced through reqCh:
+// if a request is already in flight, a new one is dropped rather than
+// piling up another getStatus/wgLock round trip behind it.
 func (e *userspaceEngine) RequestStatus() {
-	// This is slightly tricky. e.getStatus() can theoretically get
-	// blocked inside wireguard for a while, and RequestStatus() is
-	// sometimes called from a goroutine, so we don't want a lot of
-	// them hanging around. On the other hand, requesting multiple
-	// status updates simultaneously is pointless anyway; they will
-	// all say the same thing.
-
-	// Enqueue at most one request. If one is in progress already, this
-	// adds one more to the queue. If one has been requested but not
-	// started, it is a no-op.
 	select {
 	case e.reqCh <- struct{}{}:
 	default:
+		return
 	}
 
-	// Dequeue at most one request. Another thread may have already
-	// dequeued the request we enqueued above, which is fine, since the
-	// information is guaranteed to be at least as recent as the current
-	// call to RequestStatus().
-	select {
-	case <-e.reqCh:
+	go func() {
+		defer func() { <-e.reqCh }()
 		s, err := e.getStatus()
 		if s == nil && err == nil {
 			e.logf("RequestStatus: weird: both s and err are nil")
@@ -544,14 +830,17 @@ func (e *userspaceEngine) RequestStatus() {
 		if cb := e.getStatusCallback(); cb != nil {
 			cb(s, err)
 		}
-	default:
-	}
+	}()
 }
 
 func (e *userspaceEngine) Close() {
 	e.mu.Lock()
-	for key, cancel := range e.pingers {
-		delete(e.pingers, key)
+	for key, cancel := range e.probeCancels {
+		delete(e.probeCancels, key)
+		cancel()
+	}
+	for key, cancel := range e.mtuProbers {
+		delete(e.mtuProbers, key)
 		cancel()
 	}
 	e.mu.Unlock()
@@ -597,6 +886,32 @@ func (e *userspaceEngine) LinkChange(isExpensive bool) {
 		e.magicConn.Rebind()
 	}
 	e.magicConn.ReSTUN(why)
+
+	e.reprobePeerMTUs()
+}
+
+// reprobePeerMTUs re-runs path MTU discovery against every peer we've
+// handshaked with. It runs only from LinkChange, which fires whenever
+// the monitor notices a network change (there is no separate timer),
+// and we additionally rate-limit ourselves to at most once per
+// pmtuReprobeInterval so a flapping link doesn't spray probes forever.
+func (e *userspaceEngine) reprobePeerMTUs() {
+	e.mu.Lock()
+	if now := time.Now(); now.Sub(e.lastMTUProbe) < pmtuReprobeInterval {
+		e.mu.Unlock()
+		return
+	} else {
+		e.lastMTUProbe = now
+	}
+	peerIPs := make(map[wgcfg.Key][]wgcfg.IP, len(e.peerIPs))
+	for k, v := range e.peerIPs {
+		peerIPs[k] = v
+	}
+	e.mu.Unlock()
+
+	for peerKey, ips := range peerIPs {
+		go e.discoverPeerMTU(peerKey, ips)
+	}
 }
 
 func getLinkState() (*interfaces.State, error) {
@@ -641,6 +956,12 @@ func diagnoseTUNFailure(logf logger.Logf) {
 	switch runtime.GOOS {
 	case "linux":
 		diagnoseLinuxTUNFailure(logf)
+	case "darwin":
+		diagnoseDarwinTUNFailure(logf)
+	case "windows":
+		diagnoseWindowsTUNFailure(logf)
+	case "freebsd":
+		diagnoseFreebsdTUNFailure(logf)
 	default:
 		logf("no TUN failure diagnostics for OS %q", runtime.GOOS)
 	}
@@ -709,3 +1030,57 @@ func linuxDistro() string {
 	}
 	return ""
 }
+
+func diagnoseDarwinTUNFailure(logf logger.Logf) {
+	if os.Geteuid() != 0 {
+		logf("not running as root; utun creation requires root or the com.apple.developer.networking.networkextension entitlement")
+	}
+
+	if exe, err := os.Executable(); err != nil {
+		logf("can't find own executable path: %v", err)
+	} else if out, err := exec.Command("codesign", "-d", "--entitlements", ":-", exe).CombinedOutput(); err != nil {
+		logf("codesign -d --entitlements %s: %v, %s", exe, err, out)
+	} else if !bytes.Contains(out, []byte("com.apple.developer.networking.networkextension")) {
+		logf("binary at %s is missing the com.apple.developer.networking.networkextension entitlement; utun creation will fail", exe)
+	}
+
+	if out, err := exec.Command("sysctl", "net.inet.ip.fw.enable").CombinedOutput(); err != nil {
+		logf("sysctl net.inet.ip.fw.enable: %v, %s", err, out)
+	} else {
+		logf("%s", bytes.TrimSpace(out))
+	}
+}
+
+func diagnoseWindowsTUNFailure(logf logger.Logf) {
+	if out, err := exec.Command("sc", "query", "Wintun").CombinedOutput(); err != nil {
+		logf("'sc query Wintun' failed: %v, %s", err, out)
+	} else {
+		logf("Wintun service state: %s", bytes.TrimSpace(out))
+	}
+
+	sysDir := os.Getenv("SystemRoot")
+	if sysDir == "" {
+		sysDir = `C:\Windows`
+	}
+	for _, subdir := range []string{"System32", "SysWOW64"} {
+		path := sysDir + `\` + subdir + `\wintun.dll`
+		if fi, err := os.Stat(path); err != nil {
+			logf("%s: not found", path)
+		} else {
+			logf("%s: %d bytes", path, fi.Size())
+		}
+	}
+}
+
+func diagnoseFreebsdTUNFailure(logf logger.Logf) {
+	if out, err := exec.Command("kldstat", "-m", "if_tun").CombinedOutput(); err == nil {
+		logf("if_tun kernel module already loaded: %s", bytes.TrimSpace(out))
+		return
+	}
+	out, err := exec.Command("kldload", "if_tun").CombinedOutput()
+	if err != nil {
+		logf("'kldload if_tun' failed: %v, %s", err, out)
+		return
+	}
+	logf("'kldload if_tun' succeeded; if TUN still fails, check /dev/tun permissions")
+}